@@ -34,6 +34,10 @@ func ActivateEndpoint(options *ActivateEndpointOptions, activation *Activation)
 	}
 
 	res, err := client.Do(Config, req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.DrainBody(res)
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
@@ -59,6 +63,10 @@ func DeactivateEndpoint(options *ActivateEndpointOptions, activation *Activation
 	}
 
 	res, err := client.Do(Config, req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.DrainBody(res)
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
@@ -195,6 +195,10 @@ func RemoveEndpoint(endpointId int) (*Endpoint, error) {
 	}
 
 	res, err := client.Do(Config, req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.DrainBody(res)
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
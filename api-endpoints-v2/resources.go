@@ -62,6 +62,9 @@ func GetResources(endpointId int, version int) (*Resources, error) {
 	}
 
 	res, err := client.Do(Config, req)
+	if err != nil {
+		return nil, err
+	}
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
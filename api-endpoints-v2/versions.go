@@ -65,6 +65,9 @@ func ListVersions(options *ListVersionsOptions) (*Versions, error) {
 	}
 
 	res, err := client.Do(Config, req)
+	if err != nil {
+		return nil, err
+	}
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
@@ -103,6 +103,7 @@ func CollectionImportKeys(collectionId int, filename string) (*Keys, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
@@ -137,6 +138,7 @@ func RevokeKey(key int) (*Key, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	if client.IsError(res) {
 		return nil, client.NewAPIError(res)
@@ -3,7 +3,10 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/edgegrid"
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/jsonhooks-v1"
 	"io"
@@ -16,6 +19,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -24,10 +28,117 @@ var (
 	UserAgent = "Akamai-Open-Edgegrid-golang/" + libraryVersion + " golang/" + strings.TrimPrefix(runtime.Version(), "go")
 	// Client is the *http.Client to use
 	Client = http.DefaultClient
+	// AllowedHosts, when non-empty, restricts NewRequest to hosts equal to
+	// one of these domains or a subdomain of one, so a misconfigured
+	// Config.Host cannot send signed requests elsewhere. Matching is
+	// case-insensitive and on a label boundary, and a leading dot is
+	// optional. Set it to AkamaiAPIHosts to allow only Akamai API hosts. It
+	// is empty, allowing any host, by default.
+	AllowedHosts []string
+	// DefaultTimeout, when positive, bounds Do calls whose request context
+	// has no deadline, including reading the response body. A deadline
+	// already set by the caller is never shortened. Unset by default.
+	DefaultTimeout time.Duration
+	// EndpointTimeouts overrides DefaultTimeout for matching requests, so
+	// quick reads and slow bulk writes can each get a fitting bound. A key
+	// is a method ("GET"), a path prefix ("/config-dns/v2/zones") or both
+	// ("POST /config-dns/v2/zones"). The most specific matching key wins:
+	// the longest path prefix, then one that also names the method. Calls
+	// matching no key fall back to DefaultTimeout. Empty by default.
+	EndpointTimeouts map[string]time.Duration
+	// RequireDeadline makes Do fail fast with ErrNoDeadline when neither the
+	// request context, DefaultTimeout nor Client.Timeout bounds the call.
+	// Off by default.
+	RequireDeadline bool
+	// LogCurl makes Do log every signed request as an equivalent curl
+	// command, at the same trace level as edgegrid.PrintHttpRequest, with
+	// credentials redacted. Off by default.
+	LogCurl bool
+	// RequestIDFunc, when set, is called once per Do call to generate an ID
+	// sent in the RequestIDHeader header of every attempt, including
+	// retries, so one logical operation can be traced across services. A
+	// request that already carries the header keeps it. Unset by default.
+	RequestIDFunc func() string
+	// RequestIDHeader is the header carrying the ID from RequestIDFunc
+	RequestIDHeader = "X-Request-ID"
+	// AkamaiRequestIDHeader is the response header carrying Akamai's own ID
+	// for a request. When RequestIDFunc is set, Do logs which Akamai ID each
+	// attempt was given, to match our IDs against Akamai support logs.
+	AkamaiRequestIDHeader = "X-Trace-Id"
+	// Retry configures automatic retries in Do. The zero value sends each
+	// request once. Set it before issuing requests.
+	Retry RetryPolicy
+	// Hedge configures hedged requests in Do. The zero value sends each
+	// attempt once. Set it before issuing requests.
+	Hedge HedgePolicy
+	// AkamaiAPIHosts lists the domains Akamai OPEN API credentials are issued for
+	AkamaiAPIHosts = []string{".akamaiapis.net"}
 
 	reqLock sync.Mutex
+
+	inFlight     chan struct{}
+	inFlightLock sync.Mutex
+
+	// cancelAll is the parent of every Do call, replaced by CancelAll
+	cancelAll, cancelAllFunc = context.WithCancel(context.Background())
+	cancelAllLock            sync.Mutex
+
+	bodyBufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
 )
 
+// maxPooledBuffer is the largest buffer BodyJSON returns to bodyBufferPool;
+// larger ones are left to the GC rather than pinned by the pool
+const maxPooledBuffer = 1 << 20
+
+// SetMaxConcurrency caps how many requests Do sends at the same time. A
+// request stays in flight until its response body is closed, so the limit
+// also bounds connections busy streaming bodies. Once n requests are in
+// flight, further calls block until one completes or the request's context
+// is done. A value of n <= 0 removes the limit.
+func SetMaxConcurrency(n int) {
+	inFlightLock.Lock()
+	defer inFlightLock.Unlock()
+
+	if n <= 0 {
+		inFlight = nil
+		return
+	}
+	inFlight = make(chan struct{}, n)
+}
+
+// CancelAll cancels every Do call in flight, including any reading of their
+// response bodies, which then fail with context.Canceled. It is meant for
+// shutdown, to stop all traffic without tracking each request's context.
+// Calls started after CancelAll returns are not affected. A call still ends
+// when its own request context is done; CancelAll only adds a second way to
+// stop it.
+func CancelAll() {
+	cancelAllLock.Lock()
+	defer cancelAllLock.Unlock()
+
+	cancelAllFunc()
+	cancelAll, cancelAllFunc = context.WithCancel(context.Background())
+}
+
+// withCancelAll returns a copy of parent that is also cancelled by CancelAll
+func withCancelAll(parent context.Context) (context.Context, context.CancelFunc) {
+	cancelAllLock.Lock()
+	all := cancelAll
+	cancelAllLock.Unlock()
+
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-all.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 // NewRequest creates an HTTP request that can be sent to Akamai APIs. A relative URL can be provided in path, which will be resolved to the
 // Host specified in Config. If body is specified, it will be sent as the request body.
 func NewRequest(config edgegrid.Config, method, path string, body io.Reader) (*http.Request, error) {
@@ -55,6 +166,9 @@ func NewRequest(config edgegrid.Config, method, path string, body io.Reader) (*h
 	}
 
 	u := baseURL.ResolveReference(rel)
+	if !hostAllowed(u.Hostname()) {
+		return nil, fmt.Errorf("host %q is not in AllowedHosts", u.Hostname())
+	}
 	if config.AccountKey != "" {
 		q := u.Query()
 		q.Add("accountSwitchKey", config.AccountKey)
@@ -71,6 +185,20 @@ func NewRequest(config edgegrid.Config, method, path string, body io.Reader) (*h
 	return req, nil
 }
 
+func hostAllowed(host string) bool {
+	if len(AllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range AllowedHosts {
+		allowed = strings.TrimPrefix(strings.ToLower(allowed), ".")
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
 // NewJSONRequest creates an HTTP request that can be sent to the Akamai APIs with a JSON body
 // The JSON body is encoded and the Content-Type/Accept headers are set automatically.
 func NewJSONRequest(config edgegrid.Config, method, path string, body interface{}) (*http.Request, error) {
@@ -114,9 +242,14 @@ func NewMultiPartFormDataRequest(config edgegrid.Config, uriPath, filePath strin
 		return nil, err
 	}
 	_, err = io.Copy(part, file)
+	if err != nil {
+		return nil, err
+	}
 
 	for key, val := range otherFormParams {
-		_ = writer.WriteField(key, val)
+		if err = writer.WriteField(key, val); err != nil {
+			return nil, err
+		}
 	}
 	err = writer.Close()
 	if err != nil {
@@ -124,38 +257,237 @@ func NewMultiPartFormDataRequest(config edgegrid.Config, uriPath, filePath strin
 	}
 
 	req, err := NewRequest(config, "POST", uriPath, body)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	return req, err
+	return req, nil
 }
 
 // Do performs a given HTTP Request, signed with the Akamai OPEN Edgegrid
 // Authorization header. An edgegrid.Response or an error is returned.
-func Do(config edgegrid.Config, req *http.Request) (*http.Response, error) {
-	Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		req = edgegrid.AddRequestHeader(config, req)
+//
+// Redirects to the same host are followed and re-signed, since the signature
+// covers the path. Redirects to another host are not followed, as the request
+// would need to be signed for a host the credentials were not issued for;
+// the returned error wraps a *RedirectError instead. The same applies to
+// redirects that change the scheme or leave https, which would send the
+// Authorization header in plaintext.
+//
+// When Retry is configured, throttled and failed attempts are retried as
+// described on RetryPolicy. CancelAll stops every call in flight.
+func Do(config edgegrid.Config, req *http.Request) (res *http.Response, err error) {
+	if RequestIDFunc != nil && req.Header.Get(RequestIDHeader) == "" {
+		// Set before any retry, so that every attempt carries the same ID
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, RequestIDFunc())
+	}
+
+	ctx, cancel := withCancelAll(req.Context())
+	req = req.WithContext(ctx)
+	defer func() {
+		if err != nil {
+			cancel()
+			return
+		}
+		res.Body = &closeHook{ReadCloser: res.Body, hook: cancel}
+	}()
+
+	if _, ok := req.Context().Deadline(); !ok {
+		if timeout := defaultTimeout(req); timeout > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			req = req.WithContext(ctx)
+			defer func() {
+				if err != nil {
+					cancel()
+					return
+				}
+				// The timeout covers reading the body, so cancel only once it is closed
+				res.Body = &closeHook{ReadCloser: res.Body, hook: cancel}
+			}()
+		}
+	}
+
+	if RequireDeadline && Client.Timeout == 0 {
+		if _, ok := req.Context().Deadline(); !ok {
+			return nil, ErrNoDeadline
+		}
+	}
+
+	if Retry.MaxAttempts > 1 {
+		return doWithRetry(config, req, Retry)
+	}
+	res, err = sendHedged(config, req)
+	if Retry.OnRetrySequence != nil {
+		Retry.OnRetrySequence([]Attempt{newAttempt(res, err)})
+	}
+	return res, err
+}
+
+// defaultTimeout returns the timeout for req from EndpointTimeouts, or
+// DefaultTimeout when no key matches
+func defaultTimeout(req *http.Request) time.Duration {
+	timeout, best := DefaultTimeout, -1
+	for key, d := range EndpointTimeouts {
+		method, prefix := key, ""
+		if i := strings.IndexByte(key, '/'); i >= 0 {
+			method, prefix = strings.TrimSpace(key[:i]), key[i:]
+		}
+		if method != "" && !strings.EqualFold(method, req.Method) || !strings.HasPrefix(req.URL.Path, prefix) {
+			continue
+		}
+		score := 2 * len(prefix)
+		if method != "" {
+			score++
+		}
+		if score > best {
+			timeout, best = d, score
+		}
+	}
+	return timeout
+}
+
+// send signs and sends a single attempt of req, holding a concurrency slot
+// until the response body is closed
+func send(config edgegrid.Config, req *http.Request) (res *http.Response, err error) {
+	if Retry.PauseOnThrottle {
+		if err := waitThrottle(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	inFlightLock.Lock()
+	slots := inFlight
+	inFlightLock.Unlock()
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+			defer func() {
+				release := func() { <-slots }
+				if err != nil {
+					release()
+					return
+				}
+				// The connection stays busy until the body is closed
+				res.Body = &closeHook{ReadCloser: res.Body, hook: release}
+			}()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	c := *Client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host != via[0].URL.Host || req.URL.Scheme != via[0].URL.Scheme || req.URL.Scheme != "https" {
+			return &RedirectError{From: via[len(via)-1].URL, To: req.URL}
+		}
+		if Client.CheckRedirect != nil {
+			if err := Client.CheckRedirect(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		edgegrid.AddRequestHeader(config, req)
 		return nil
 	}
 
 	req = edgegrid.AddRequestHeader(config, req)
-	res, err := Client.Do(req)
+	if LogCurl {
+		edgegrid.PrintCurlRequest(req)
+	}
+	res, err = c.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if date := res.Header.Get("Date"); date != "" {
+		edgegrid.EdgegridLog.Debugf("Server time: '%s'", date)
+	}
+	if id := req.Header.Get(RequestIDHeader); id != "" && RequestIDFunc != nil {
+		edgegrid.EdgegridLog.Debugf("Request ID %s: %s %s returned %s, Akamai request ID '%s'",
+			id, req.Method, req.URL.Path, res.Status, res.Header.Get(AkamaiRequestIDHeader))
+	}
 
 	return res, nil
 }
 
+// closeHook runs hook once its response body is first closed, releasing
+// resources held for the duration of the body read
+type closeHook struct {
+	io.ReadCloser
+	hook func()
+	once sync.Once
+}
+
+func (b *closeHook) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.hook)
+	return err
+}
+
 // BodyJSON unmarshals the Response.Body into a given data structure
+//
+// data may be any value encoding/json can decode into, including caller
+// types implementing json.Unmarshaler; PostUnmarshalJSON hooks are honored.
+//
+// A 204 No Content response, or any response with an empty body, is not
+// decoded and leaves data unchanged.
 func BodyJSON(r *http.Response, data interface{}) error {
+	defer drainAndClose(r.Body)
+
 	if data == nil {
 		return errors.New("You must pass in an interface{}")
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return err
+	if r.StatusCode == http.StatusNoContent || r.ContentLength == 0 {
+		return nil
+	}
+
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			bodyBufferPool.Put(buf)
+		}
+	}()
+
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		return &DecodeError{Err: err, BytesRead: buf.Len(), Truncated: true}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	// Unmarshal copies what it keeps, so the buffer can be reused safely
+	err := jsonhooks.Unmarshal(buf.Bytes(), data)
+	if syntaxErr, ok := err.(*json.SyntaxError); ok {
+		return &DecodeError{
+			Err:       err,
+			BytesRead: buf.Len(),
+			Truncated: strings.HasPrefix(syntaxErr.Error(), "unexpected end of JSON input"),
+		}
 	}
-	err = jsonhooks.Unmarshal(body, data)
 
 	return err
 }
+
+// DrainBody discards whatever is left of res.Body and closes it, so the
+// connection can be reused and any SetMaxConcurrency slot is released. Defer
+// it in callers that may return without passing res to BodyJSON or
+// NewAPIError; it is safe to call after either has consumed the body.
+func DrainBody(res *http.Response) {
+	if res == nil {
+		return
+	}
+	drainAndClose(res.Body)
+}
+
+// drainAndClose reads any remaining bytes from body and closes it, so the
+// underlying connection can be returned to the pool and reused.
+func drainAndClose(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, body)
+	body.Close()
+}
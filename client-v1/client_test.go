@@ -1,11 +1,29 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/edgegrid"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -71,6 +89,701 @@ func TestNewJSONRequestWithSwitchedAccount(t *testing.T) {
 	verifyResponseConfig(t, config, req)
 }
 
+func TestNewRequestAllowedHosts(t *testing.T) {
+	AllowedHosts = AkamaiAPIHosts
+	defer func() { AllowedHosts = nil }()
+
+	tests := map[string]bool{
+		"akaa-baseurl-xxxxxxxxxxx-xxxxxxxxxxxxx.luna.akamaiapis.net/": true,
+		"https://AKAA-BASEURL.LUNA.AKAMAIAPIS.NET":                    true,
+		"akamaiapis.net.attacker.example":                             false,
+		"https://attacker.example/akamaiapis.net":                     false,
+		"notakamaiapis.net":                                           false,
+	}
+	for host, allowed := range tests {
+		t.Run(host, func(t *testing.T) {
+			_, err := NewRequest(edgegrid.Config{Host: host}, "GET", "/papi/v1/groups", nil)
+			if allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestNewRequestAllowedHostsWithoutLeadingDot(t *testing.T) {
+	AllowedHosts = []string{"akamaiapis.net"}
+	defer func() { AllowedHosts = nil }()
+
+	tests := map[string]bool{
+		"akaa-baseurl.luna.akamaiapis.net": true,
+		"akamaiapis.net":                   true,
+		"notakamaiapis.net":                false,
+		"akamaiapis.net.attacker.example":  false,
+	}
+	for host, allowed := range tests {
+		t.Run(host, func(t *testing.T) {
+			_, err := NewRequest(edgegrid.Config{Host: host}, "GET", "/papi/v1/groups", nil)
+			if allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestDoReusesConnectionAfterError(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"detail":"` + strings.Repeat("x", 8192) + `"}`))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	config := startTestServer(t, server)
+
+	for i := 0; i < 3; i++ {
+		req, err := NewRequest(config, "GET", "/error", nil)
+		assert.NoError(t, err)
+
+		res, err := Do(config, req)
+		if assert.NoError(t, err) {
+			// BodyJSON fails before decoding, but must still release the body
+			assert.Error(t, BodyJSON(res, nil))
+		}
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
+
+func TestDoResignsSameHostRedirect(t *testing.T) {
+	var auth []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	config := startTestServer(t, server)
+
+	req, err := NewRequest(config, "GET", "/old", nil)
+	assert.NoError(t, err)
+
+	res, err := Do(config, req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, auth, 2)
+	assert.True(t, strings.HasPrefix(auth[1], "EG1-HMAC-SHA256 "))
+	assert.NotEqual(t, auth[0], auth[1])
+}
+
+func TestDoRefusesCrossHostRedirect(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com/elsewhere", http.StatusFound)
+	}))
+	config := startTestServer(t, server)
+
+	req, err := NewRequest(config, "GET", "/old", nil)
+	assert.NoError(t, err)
+
+	_, err = Do(config, req)
+	var redirectErr *RedirectError
+	if assert.True(t, errors.As(err, &redirectErr)) {
+		assert.Equal(t, "example.com", redirectErr.To.Host)
+	}
+}
+
+func TestDoRefusesPlaintextRedirect(t *testing.T) {
+	var requests int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Redirect(w, r, "http://"+r.Host+"/new", http.StatusFound)
+	}))
+	config := startTestServer(t, server)
+
+	req, err := NewRequest(config, "GET", "/old", nil)
+	assert.NoError(t, err)
+
+	_, err = Do(config, req)
+	var redirectErr *RedirectError
+	if assert.True(t, errors.As(err, &redirectErr)) {
+		assert.Equal(t, "http", redirectErr.To.Scheme)
+		assert.Equal(t, req.URL.Host, redirectErr.To.Host)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDoMaxConcurrency(t *testing.T) {
+	var current, peak int32
+	arrived := make(chan struct{}, 5)
+	release := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		arrived <- struct{}{}
+		<-release
+		atomic.AddInt32(&current, -1)
+	}))
+	config := startTestServer(t, server)
+
+	SetMaxConcurrency(2)
+	defer SetMaxConcurrency(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := NewRequest(config, "GET", "/slow", nil)
+			res, err := Do(config, req)
+			if assert.NoError(t, err) {
+				res.Body.Close()
+			}
+		}()
+	}
+
+	<-arrived
+	<-arrived
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+	assert.Len(t, arrived, 3)
+}
+
+func TestDoMaxConcurrencyHoldsSlotUntilBodyClosed(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"partial":`))
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/streaming" {
+			<-release
+		}
+		w.Write([]byte(`true}`))
+	}))
+	config := startTestServer(t, server)
+	defer close(release)
+
+	SetMaxConcurrency(1)
+	defer SetMaxConcurrency(0)
+
+	req, _ := NewRequest(config, "GET", "/streaming", nil)
+	streaming, err := Do(config, req)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ = NewRequest(config, "GET", "/next", nil)
+	_, err = Do(config, req.WithContext(ctx))
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	streaming.Body.Close()
+	streaming.Body.Close()
+
+	req, _ = NewRequest(config, "GET", "/next", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.NoError(t, BodyJSON(res, &map[string]interface{}{}))
+	}
+	assert.Len(t, inFlight, 0)
+}
+
+func TestDoMaxConcurrencyContextDone(t *testing.T) {
+	SetMaxConcurrency(1)
+	defer SetMaxConcurrency(0)
+
+	inFlight <- struct{}{}
+	defer func() { <-inFlight }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	config := edgegrid.Config{Host: "akaa-baseurl.luna.akamaiapis.net"}
+	req, _ := NewRequest(config, "GET", "/blocked", nil)
+	_, err := Do(config, req.WithContext(ctx))
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestBodyJSONNoContent(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	config := startTestServer(t, server)
+
+	req, err := NewRequest(config, "DELETE", "/resource", nil)
+	assert.NoError(t, err)
+
+	res, err := Do(config, req)
+	assert.NoError(t, err)
+	assert.True(t, IsSuccess(res))
+
+	data := map[string]interface{}{}
+	assert.NoError(t, BodyJSON(res, &data))
+	assert.Empty(t, data)
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestBodyJSONNilDataClosesBody(t *testing.T) {
+	body := &closeTracker{Reader: strings.NewReader(`{"ok":true}`)}
+	res := &http.Response{StatusCode: http.StatusOK, Body: body, ContentLength: -1}
+
+	assert.Error(t, BodyJSON(res, nil))
+	assert.True(t, body.closed)
+}
+
+func TestNewMultiPartFormDataRequest(t *testing.T) {
+	var config edgegrid.Config
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assertSigned(t, config, r, body)
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		assert.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "FULL", r.FormValue("importType"))
+
+		file, header, err := r.FormFile("importFile")
+		if assert.NoError(t, err) {
+			defer file.Close()
+			content, _ := ioutil.ReadAll(file)
+			assert.Equal(t, "rules.json", header.Filename)
+			assert.Equal(t, `{"rules":{}}`, string(content))
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	config = startTestServer(t, server)
+
+	filePath := writeTempFile(t, "rules.json", `{"rules":{}}`)
+	req, err := NewMultiPartFormDataRequest(config, "/import?contractId=ctr_1", filePath, map[string]string{"importType": "FULL"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		res.Body.Close()
+	}
+}
+
+func TestNewMultiPartFormDataRequestMissingFile(t *testing.T) {
+	config := edgegrid.Config{Host: "akaa-baseurl.luna.akamaiapis.net"}
+	_, err := NewMultiPartFormDataRequest(config, "/import", "does-not-exist.json", nil)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewMultiPartFormDataRequestHostNotAllowed(t *testing.T) {
+	AllowedHosts = AkamaiAPIHosts
+	defer func() { AllowedHosts = nil }()
+
+	filePath := writeTempFile(t, "rules.json", `{"rules":{}}`)
+	config := edgegrid.Config{Host: "attacker.example"}
+	req, err := NewMultiPartFormDataRequest(config, "/import", filePath, nil)
+	assert.Nil(t, req)
+	assert.EqualError(t, err, `host "attacker.example" is not in AllowedHosts`)
+}
+
+// writeTempFile writes content to a file named name in a directory removed
+// when the test ends, and returns its path
+func writeTempFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertSigned recomputes the EG1-HMAC-SHA256 signature of r, as the server
+// received it with body, from the credentials in config, and checks it
+// against the one in the Authorization header
+func assertSigned(t *testing.T, config edgegrid.Config, r *http.Request, body []byte) {
+	auth := r.Header.Get("Authorization")
+	i := strings.LastIndex(auth, "signature=")
+	if !assert.True(t, i >= 0, "no signature in %q", auth) {
+		return
+	}
+	unsigned, signature := auth[:i], auth[i+len("signature="):]
+	prefix := "EG1-HMAC-SHA256 client_token=" + config.ClientToken + ";access_token=" + config.AccessToken + ";"
+	assert.True(t, strings.HasPrefix(unsigned, prefix), "unexpected Authorization header %q", auth)
+
+	var timestamp string
+	for _, field := range strings.Split(strings.TrimPrefix(unsigned, prefix), ";") {
+		if strings.HasPrefix(field, "timestamp=") {
+			timestamp = strings.TrimPrefix(field, "timestamp=")
+		}
+	}
+	var contentHash string
+	if r.Method == "POST" && len(body) > 0 {
+		sum := sha256.Sum256(body)
+		contentHash = base64.StdEncoding.EncodeToString(sum[:])
+	}
+	sign := func(key, message string) string {
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write([]byte(message))
+		return base64.StdEncoding.EncodeToString(h.Sum(nil))
+	}
+	data := strings.Join([]string{r.Method, "https", r.Host, r.RequestURI, "", contentHash, unsigned}, "\t")
+	assert.Equal(t, sign(sign(config.ClientSecret, timestamp), data), signature)
+}
+
+type upperHostname string
+
+func (h *upperHostname) UnmarshalJSON(b []byte) error {
+	*h = upperHostname(strings.ToUpper(strings.Trim(string(b), `"`)))
+	return nil
+}
+
+func TestBodyJSONGzipResponse(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"hostname":"www.example.com"}`))
+		gz.Close()
+	}))
+	config := startTestServer(t, server)
+
+	req, _ := NewJSONRequest(config, "GET", "/compressed", nil)
+	res, err := Do(config, req)
+	assert.NoError(t, err)
+	assert.True(t, res.Uncompressed)
+
+	data := JSONBody{}
+	assert.NoError(t, BodyJSON(res, &data))
+	assert.Equal(t, "www.example.com", data["hostname"])
+}
+
+func TestBodyJSONCustomUnmarshaler(t *testing.T) {
+	body := `{"hostname":"www.example.com"}`
+	res := &http.Response{
+		StatusCode:    http.StatusOK,
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+	}
+
+	var data struct {
+		Hostname upperHostname `json:"hostname"`
+	}
+	assert.NoError(t, BodyJSON(res, &data))
+	assert.Equal(t, upperHostname("WWW.EXAMPLE.COM"), data.Hostname)
+}
+
+func TestBodyJSONDecodeError(t *testing.T) {
+	tests := map[string]struct {
+		body      string
+		truncated bool
+	}{
+		"truncated": {`{"hostname":"www.exa`, true},
+		"malformed": {`{"hostname":www.example.com}`, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			res := &http.Response{
+				StatusCode:    http.StatusOK,
+				ContentLength: -1,
+				Body:          ioutil.NopCloser(strings.NewReader(test.body)),
+			}
+			err := BodyJSON(res, &JSONBody{})
+
+			var decodeErr *DecodeError
+			if assert.True(t, errors.As(err, &decodeErr)) {
+				assert.Equal(t, test.truncated, decodeErr.Truncated)
+				assert.Equal(t, len(test.body), decodeErr.BytesRead)
+			}
+		})
+	}
+}
+
+func TestBodyJSONConnectionDropped(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`{"hostname":`))
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	config := startTestServer(t, server)
+
+	req, _ := NewRequest(config, "GET", "/truncated", nil)
+	res, err := Do(config, req)
+	assert.NoError(t, err)
+
+	err = BodyJSON(res, &JSONBody{})
+	var decodeErr *DecodeError
+	if assert.True(t, errors.As(err, &decodeErr)) {
+		assert.True(t, decodeErr.Truncated)
+		assert.Equal(t, 12, decodeErr.BytesRead)
+	}
+}
+
+func BenchmarkBodyJSON(b *testing.B) {
+	body := `{"items":[` + strings.Repeat(`{"hostname":"www.example.com","enabled":true},`, 1000) + `{}]}`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		res := &http.Response{
+			StatusCode:    http.StatusOK,
+			ContentLength: int64(len(body)),
+			Body:          ioutil.NopCloser(strings.NewReader(body)),
+		}
+		data := JSONBody{}
+		if err := BodyJSON(res, &data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDoRequireDeadline(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	config := startTestServer(t, server)
+
+	RequireDeadline = true
+	defer func() { RequireDeadline = false }()
+
+	req, _ := NewRequest(config, "GET", "/unbounded", nil)
+	_, err := Do(config, req)
+	assert.Equal(t, ErrNoDeadline, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := Do(config, req.WithContext(ctx))
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+
+	Client.Timeout = time.Second
+	res, err = Do(config, req)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+}
+
+func TestDoDefaultTimeout(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"partial":`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	config := startTestServer(t, server)
+
+	DefaultTimeout = 50 * time.Millisecond
+	defer func() { DefaultTimeout = 0 }()
+
+	req, _ := NewRequest(config, "GET", "/stalled", nil)
+	start := time.Now()
+	res, err := Do(config, req)
+	assert.NoError(t, err)
+
+	err = BodyJSON(res, &JSONBody{})
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestDoDefaultTimeoutKeepsCallerDeadline(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+
+	DefaultTimeout = 10 * time.Millisecond
+	defer func() { DefaultTimeout = 0 }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := NewRequest(config, "GET", "/slow", nil)
+	res, err := Do(config, req.WithContext(ctx))
+	if assert.NoError(t, err) {
+		assert.NoError(t, BodyJSON(res, &JSONBody{}))
+	}
+}
+
+func TestDoEndpointTimeouts(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+
+	DefaultTimeout = 10 * time.Millisecond
+	EndpointTimeouts = map[string]time.Duration{"PUT /config-dns/v2/zones": time.Second}
+	defer func() { DefaultTimeout, EndpointTimeouts = 0, nil }()
+
+	req, _ := NewJSONRequest(config, "PUT", "/config-dns/v2/zones/example.com", map[string]string{})
+	if res, err := Do(config, req); assert.NoError(t, err) {
+		assert.NoError(t, BodyJSON(res, &JSONBody{}))
+	}
+
+	req, _ = NewRequest(config, "GET", "/config-dns/v2/zones/example.com", nil)
+	_, err := Do(config, req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	DefaultTimeout = time.Minute
+	EndpointTimeouts = map[string]time.Duration{
+		"GET":                           time.Second,
+		"/config-dns/v2":                2 * time.Second,
+		"/config-dns/v2/zones":          3 * time.Second,
+		"POST /config-dns/v2/zones":     4 * time.Second,
+		"GET /papi/v1/properties/":      5 * time.Second,
+		"delete /papi/v1/properties/p1": 6 * time.Second,
+	}
+	defer func() { DefaultTimeout, EndpointTimeouts = 0, nil }()
+
+	tests := []struct {
+		method, path string
+		want         time.Duration
+	}{
+		{"GET", "/papi/v1/groups", time.Second},
+		{"PUT", "/papi/v1/groups", time.Minute},
+		{"GET", "/config-dns/v2/zones/example.com", 3 * time.Second},
+		{"POST", "/config-dns/v2/zones", 4 * time.Second},
+		{"PUT", "/config-dns/v2/tsig-keys", 2 * time.Second},
+		{"GET", "/papi/v1/properties/p1", 5 * time.Second},
+		{"DELETE", "/papi/v1/properties/p1/versions/2", 6 * time.Second},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		assert.Equal(t, test.want, defaultTimeout(req), "%s %s", test.method, test.path)
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	arrivals := make(chan struct{}, 3)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/after" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.URL.Path == "/body" {
+			w.Write([]byte(`{"partial":`))
+			w.(http.Flusher).Flush()
+		}
+		arrivals <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	config := startTestServer(t, server)
+	edgegrid.SetupLogging()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			req, _ := NewRequest(config, "GET", "/stalled", nil)
+			_, err := Do(config, req)
+			errs <- err
+		}()
+	}
+	req, _ := NewRequest(config, "GET", "/body", nil)
+	res, err := Do(config, req)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		<-arrivals
+	}
+
+	CancelAll()
+	for i := 0; i < 2; i++ {
+		assert.True(t, errors.Is(<-errs, context.Canceled))
+	}
+	assert.Error(t, BodyJSON(res, &JSONBody{}))
+
+	req, _ = NewRequest(config, "GET", "/after", nil)
+	if res, err := Do(config, req); assert.NoError(t, err) {
+		assert.NoError(t, BodyJSON(res, &JSONBody{}))
+	}
+}
+
+func TestDoLogCurl(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	config.AccessToken = "akab-access-token"
+	config.ClientToken = "akab-client-token"
+
+	edgegrid.SetupLogging()
+	hook := logtest.NewLocal(edgegrid.EdgegridLog)
+	level := edgegrid.EdgegridLog.GetLevel()
+	edgegrid.EdgegridLog.SetLevel(logrus.TraceLevel)
+	defer edgegrid.EdgegridLog.SetLevel(level)
+
+	LogCurl = true
+	defer func() { LogCurl = false }()
+
+	req, _ := NewJSONRequest(config, "POST", "/papi/v1/cpcodes", map[string]string{"cpcodeName": "example"})
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+
+	var curl []string
+	for _, entry := range hook.AllEntries() {
+		if strings.HasPrefix(entry.Message, "curl ") {
+			curl = append(curl, entry.Message)
+			assert.Equal(t, logrus.TraceLevel, entry.Level)
+		}
+	}
+	if assert.Len(t, curl, 1) {
+		assert.Contains(t, curl[0], "-X POST '"+server.URL+"/papi/v1/cpcodes'")
+		assert.Contains(t, curl[0], `--data-binary '{"cpcodeName":"example"}'`)
+		assert.Contains(t, curl[0], edgegrid.CurlPlaceholderAuthorization)
+		assert.NotContains(t, curl[0], config.ClientToken)
+		assert.NotContains(t, curl[0], config.AccessToken)
+	}
+}
+
+// startTestServer starts server with TLS, points Client at it for the
+// duration of the test and returns a Config signing requests for it
+func startTestServer(t *testing.T, server *httptest.Server) edgegrid.Config {
+	server.StartTLS()
+	defaultClient := Client
+	Client = server.Client()
+	t.Cleanup(func() {
+		Client = defaultClient
+		server.Close()
+	})
+
+	return edgegrid.Config{
+		Host:         server.URL,
+		AccessToken:  "local-config",
+		ClientSecret: "local-config",
+		ClientToken:  "local-config",
+		MaxBody:      131072,
+	}
+}
+
 func verifyResponseConfig(t *testing.T, config edgegrid.Config, req *http.Request) {
 	resp, err := Do(config, req)
 	assert.NotNil(t, resp)
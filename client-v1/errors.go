@@ -1,14 +1,20 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/jsonhooks-v1"
 )
 
+// ErrNoDeadline is returned by Do when RequireDeadline is set and the
+// request would otherwise run unbounded
+var ErrNoDeadline = errors.New("request has no deadline and Client.Timeout is not set")
+
 // APIError exposes an Akamai OPEN Edgegrid Error
 type APIError struct {
 	error
@@ -55,6 +61,7 @@ func (error APIError) Error() string {
 func NewAPIError(response *http.Response) APIError {
 	// TODO: handle this error
 	body, _ := ioutil.ReadAll(response.Body)
+	drainAndClose(response.Body)
 
 	return NewAPIErrorFromBody(response, body)
 }
@@ -76,6 +83,65 @@ func NewAPIErrorFromBody(response *http.Response, body []byte) APIError {
 	return error
 }
 
+// RedirectError is returned (wrapped in a *url.Error) by Do when the API
+// redirects to a different host or scheme than the one the request was
+// signed for
+type RedirectError struct {
+	From *url.URL
+	To   *url.URL
+}
+
+func (error *RedirectError) Error() string {
+	return fmt.Sprintf("refusing to follow redirect from %s://%s to a different host or scheme: %s", error.From.Scheme, error.From.Host, error.To)
+}
+
+// DecodeError is returned by BodyJSON when the response body could not be
+// read in full or is not valid JSON. Truncated distinguishes a body cut
+// short, e.g. by a dropped connection, from one the server sent malformed.
+type DecodeError struct {
+	Err       error
+	BytesRead int
+	Truncated bool
+}
+
+func (error *DecodeError) Error() string {
+	if error.Truncated {
+		return fmt.Sprintf("response body truncated after %d bytes: %s", error.BytesRead, error.Err)
+	}
+	return fmt.Sprintf("malformed JSON in %d byte response body: %s", error.BytesRead, error.Err)
+}
+
+// Unwrap returns the underlying read or syntax error
+func (error *DecodeError) Unwrap() error {
+	return error.Err
+}
+
+// RetryError is returned by Do when a request that was retried ultimately
+// fails with an error, such as its context's deadline passing while waiting
+// out a 429. It records why the earlier attempts were retried, so that a
+// throttling-induced timeout is not mistaken for a slow API.
+type RetryError struct {
+	// Attempts is the number of attempts sent before the call failed
+	Attempts int
+	// LastStatus is the status code of the last attempt that got a response
+	LastStatus int
+	// Throttled counts the attempts answered with 429 Too Many Requests
+	Throttled int
+	Err       error
+}
+
+func (error *RetryError) Error() string {
+	if error.Throttled > 0 {
+		return fmt.Sprintf("%s after %d attempts (%d throttled with 429, last status %d)", error.Err, error.Attempts, error.Throttled, error.LastStatus)
+	}
+	return fmt.Sprintf("%s after %d attempts (last status %d)", error.Err, error.Attempts, error.LastStatus)
+}
+
+// Unwrap returns the error that ended the call
+func (error *RetryError) Unwrap() error {
+	return error.Err
+}
+
 // IsInformational determines if a response was informational (1XX status)
 func IsInformational(r *http.Response) bool {
 	return r.StatusCode > 99 && r.StatusCode < 200
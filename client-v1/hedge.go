@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/edgegrid"
+)
+
+// HedgePolicy configures request hedging in Do, to cut the tail latency of
+// reads.
+//
+// When an idempotent request (GET, HEAD or OPTIONS) has had no response
+// after Delay, an identical request is sent alongside it, and again after
+// each further Delay, up to MaxHedges extra requests. The first response to
+// arrive is returned and the other requests are cancelled. If every request
+// fails, the last error is returned. Requests of other methods, and requests
+// whose body cannot be replayed through req.GetBody, are sent once.
+//
+// With Retry enabled, each attempt is hedged. Every hedged request counts
+// against the Akamai API rate limits and SetMaxConcurrency, so keep
+// MaxHedges small.
+type HedgePolicy struct {
+	// Delay is how long to wait for a response before sending each extra
+	// request
+	Delay time.Duration
+	// MaxHedges is the number of extra requests allowed per attempt.
+	// A value of 0 or less disables hedging.
+	MaxHedges int
+}
+
+// hedgeResult is the outcome of one request sent by sendHedged
+type hedgeResult struct {
+	res   *http.Response
+	err   error
+	index int
+}
+
+// sendHedged sends req as one attempt, hedged according to Hedge
+func sendHedged(config edgegrid.Config, req *http.Request) (*http.Response, error) {
+	policy := Hedge
+	if policy.MaxHedges <= 0 || !idempotent(req.Method) {
+		return send(config, req)
+	}
+
+	// Signing sets up logging on first use, so do it before signing
+	// concurrently. Each request is a copy of req, so concurrent signing
+	// never touches the caller's request.
+	edgegrid.SetupLogging()
+	results := make(chan hedgeResult, policy.MaxHedges+1)
+	var cancels []context.CancelFunc
+	launch := func() bool {
+		hedged, err := rewind(req)
+		if err != nil {
+			return false
+		}
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		index := len(cancels) - 1
+		go func() {
+			res, err := send(config, hedged.WithContext(ctx))
+			results <- hedgeResult{res: res, err: err, index: index}
+		}()
+		return true
+	}
+	if !launch() {
+		return send(config, req)
+	}
+	pending := 1
+
+	var wait <-chan time.Time
+	stop := func() bool { return false }
+	defer func() { stop() }()
+	arm := func() {
+		stop()
+		wait = nil
+		if len(cancels) <= policy.MaxHedges {
+			wait, stop = clock.NewTimer(policy.Delay)
+		}
+	}
+	arm()
+
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				for i, cancel := range cancels {
+					if i != r.index {
+						cancel()
+					}
+				}
+				go releaseHedges(results, pending)
+				r.res.Body = &closeHook{ReadCloser: r.res.Body, hook: cancels[r.index]}
+				return r.res, nil
+			}
+			cancels[r.index]()
+			if pending > 0 {
+				continue
+			}
+			// Nothing is left in flight, so hedge at once rather than wait
+			if len(cancels) > policy.MaxHedges || !launch() {
+				return nil, r.err
+			}
+			pending++
+			arm()
+		case <-wait:
+			wait = nil
+			if !launch() {
+				continue
+			}
+			edgegrid.EdgegridLog.Debugf("Hedging %s %s after %s (request %d of %d)",
+				req.Method, req.URL.Path, policy.Delay, len(cancels), policy.MaxHedges+1)
+			pending++
+			arm()
+		}
+	}
+}
+
+// releaseHedges closes the responses of the n hedged requests that lost
+func releaseHedges(results <-chan hedgeResult, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.res != nil {
+			drainAndClose(r.res.Body)
+		}
+	}
+}
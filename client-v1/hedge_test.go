@@ -0,0 +1,121 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withHedge installs policy as Hedge for the duration of the test
+func withHedge(t *testing.T, policy HedgePolicy) {
+	Hedge = policy
+	t.Cleanup(func() { Hedge = HedgePolicy{} })
+}
+
+func TestDoHedgeFirstResponseWins(t *testing.T) {
+	var calls int32
+	cancelled := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"hedged":true}`))
+	}))
+	config := startTestServer(t, server)
+	withHedge(t, HedgePolicy{Delay: 10 * time.Millisecond, MaxHedges: 1})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		var body map[string]bool
+		assert.NoError(t, BodyJSON(res, &body))
+		assert.True(t, body["hedged"])
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("slow request was not cancelled")
+	}
+}
+
+func TestDoHedgeCapsHedges(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withHedge(t, HedgePolicy{Delay: time.Millisecond, MaxHedges: 2})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+		if res, err := Do(config, req); assert.NoError(t, err) {
+			res.Body.Close()
+		}
+	}()
+	for start := time.Now(); atomic.LoadInt32(&calls) < 3 && time.Since(start) < 5*time.Second; {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	close(release)
+	<-done
+}
+
+func TestDoHedgeAfterError(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Drop the connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withHedge(t, HedgePolicy{Delay: time.Hour, MaxHedges: 1})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoHedgeSkipsNonIdempotent(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withHedge(t, HedgePolicy{Delay: time.Millisecond, MaxHedges: 2})
+
+	req, _ := NewJSONRequest(config, "POST", "/papi/v1/cpcodes", map[string]string{"cpcodeName": "example"})
+	if res, err := Do(config, req); assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
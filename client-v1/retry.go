@@ -0,0 +1,371 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/edgegrid"
+)
+
+// maxBackoff bounds the exponential delay between attempts
+const maxBackoff = time.Minute
+
+// RetryPolicy configures how Do retries throttled and failed requests.
+//
+// Idempotent requests (GET, HEAD and OPTIONS) are retried on 429 Too Many
+// Requests and on 5xx responses. Other methods are retried only on 429, which
+// tells the caller the server did not act on the request, so a retry cannot
+// duplicate a write. A Retry-After header is honored when present; otherwise
+// the delay grows exponentially from BaseDelay, with jitter. Retrying stops
+// as soon as the request's context is done. A request whose body cannot be
+// replayed through req.GetBody is sent only once.
+//
+// Idempotent requests are also retried when the connection is dropped or
+// reset, including part way through the response. To detect that, their
+// response bodies are read into memory before Do returns.
+//
+// When retries run out, Do returns the last response as usual. When a
+// retried call instead fails with an error, the error is a *RetryError
+// wrapping it.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// A value of 1 or less disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry when the response has
+	// no Retry-After header. It doubles on each subsequent retry.
+	BaseDelay time.Duration
+	// MaxRetryAfter, when positive, caps how long a Retry-After header is
+	// honored. A response asking for a longer wait is returned to the caller
+	// instead of retried. WithMaxRetryAfter overrides it per request.
+	MaxRetryAfter time.Duration
+	// PauseOnThrottle makes a retried 429 pause every Do call, not just the
+	// one that was throttled, until its retry delay has elapsed. Paused
+	// calls wait before taking a SetMaxConcurrency slot, so a batch backs
+	// off together instead of each request being throttled in turn.
+	PauseOnThrottle bool
+	// SplitDeadline, when the request's context has a deadline, gives each
+	// attempt an equal share of the time left, so one slow attempt cannot
+	// use up the caller's whole budget. An idempotent request whose attempt
+	// runs out of its share is retried. A retry whose delay would run past
+	// the deadline is skipped, and the last response or error is returned.
+	SplitDeadline bool
+	// OnRetrySequence, when set, is called once per Do call, whether it
+	// succeeds or not, with a record of every attempt made. It is called
+	// even when MaxAttempts disables retries, with the single attempt; as
+	// bodies are then not buffered, its Err covers only sending the request.
+	OnRetrySequence func([]Attempt)
+}
+
+// Attempt records one attempt of a request sent with retries enabled
+type Attempt struct {
+	// StatusCode is the response status, or 0 if no response was received
+	StatusCode int
+	// Err is the error sending the request or reading its response body
+	Err error
+	// Delay is the wait before the next attempt, or 0 for the last attempt
+	Delay time.Duration
+}
+
+// clock is the source of time for retry delays, Retry-After dates and
+// throttle pauses. Tests replace it to run backoff without waiting.
+var clock interface {
+	Now() time.Time
+	// NewTimer returns a channel that receives once d has elapsed, and a
+	// function that stops it early
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+} = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+// newAttempt records an attempt that produced res and err
+func newAttempt(res *http.Response, err error) Attempt {
+	record := Attempt{Err: err}
+	if res != nil {
+		record.StatusCode = res.StatusCode
+	}
+	return record
+}
+
+var (
+	throttleLock   sync.Mutex
+	throttledUntil time.Time
+)
+
+// throttle pauses new requests for at least d
+func throttle(d time.Duration) {
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+
+	if until := clock.Now().Add(d); until.After(throttledUntil) {
+		throttledUntil = until
+	}
+}
+
+// waitThrottle blocks until any pause set by throttle has elapsed, or ctx
+// is done
+func waitThrottle(ctx context.Context) error {
+	throttleLock.Lock()
+	until := throttledUntil
+	throttleLock.Unlock()
+
+	if d := until.Sub(clock.Now()); d > 0 {
+		return sleep(ctx, d)
+	}
+	return nil
+}
+
+type maxRetryAfterKey struct{}
+
+// WithMaxRetryAfter returns a copy of ctx that overrides
+// RetryPolicy.MaxRetryAfter for requests sent with it. A value of 0 removes
+// the cap.
+func WithMaxRetryAfter(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, maxRetryAfterKey{}, d)
+}
+
+// maxRetryAfter returns the Retry-After cap in effect for ctx
+func (policy RetryPolicy) maxRetryAfter(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(maxRetryAfterKey{}).(time.Duration); ok {
+		return d
+	}
+	return policy.MaxRetryAfter
+}
+
+func doWithRetry(config edgegrid.Config, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	attempt := req
+	retryErr := &RetryError{}
+	var attempts []Attempt
+	if policy.OnRetrySequence != nil {
+		defer func() { policy.OnRetrySequence(attempts) }()
+	}
+	for n := 1; ; n++ {
+		sent, cancel := policy.attemptContext(attempt, n)
+		res, err := sendHedged(config, sent)
+		reason := err
+		if err == nil && idempotent(req.Method) {
+			reason = bufferBody(res)
+		}
+		// An attempt cut short by its share of the deadline can be retried
+		// while the caller's own context is still live
+		timedOut := reason != nil && idempotent(req.Method) &&
+			sent.Context().Err() != nil && ctx.Err() == nil
+		retryErr.Attempts = n
+		if policy.OnRetrySequence != nil {
+			attempts = append(attempts, newAttempt(res, reason))
+		}
+
+		if n >= policy.MaxAttempts || !(timedOut || retryable(req.Method, res, reason)) {
+			return giveUp(res, err, retryErr, cancel)
+		}
+		next, rewindErr := rewind(req)
+		if rewindErr != nil {
+			return giveUp(res, err, retryErr, cancel)
+		}
+
+		delay := policy.backoff(n)
+		if res != nil {
+			retryErr.LastStatus = res.StatusCode
+			if res.StatusCode == http.StatusTooManyRequests {
+				retryErr.Throttled++
+			}
+			if d, ok := retryAfter(res); ok && reason == nil {
+				if max := policy.maxRetryAfter(ctx); max > 0 && d > max {
+					edgegrid.EdgegridLog.Debugf("Not retrying %s %s: Retry-After %s exceeds %s",
+						req.Method, req.URL.Path, d, max)
+					return giveUp(res, nil, retryErr, cancel)
+				}
+				delay = d
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && policy.SplitDeadline && time.Until(deadline) < delay {
+			edgegrid.EdgegridLog.Debugf("Not retrying %s %s: delay %s runs past the deadline",
+				req.Method, req.URL.Path, delay)
+			return giveUp(res, err, retryErr, cancel)
+		}
+		if res != nil {
+			drainAndClose(res.Body)
+		}
+		cancel()
+		if reason == nil {
+			reason = errors.New(res.Status)
+		}
+		edgegrid.EdgegridLog.Debugf("Retrying %s %s after %s (attempt %d of %d) in %s",
+			req.Method, req.URL.Path, reason, n+1, policy.MaxAttempts, delay)
+		if policy.PauseOnThrottle && res != nil && res.StatusCode == http.StatusTooManyRequests {
+			throttle(delay)
+		}
+		if policy.OnRetrySequence != nil {
+			attempts[len(attempts)-1].Delay = delay
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			retryErr.Err = err
+			return nil, retryErr
+		}
+		attempt = next
+	}
+}
+
+// attemptContext returns attempt with its share of the request's deadline
+// when policy.SplitDeadline is set, and the function releasing it. n is the
+// number of the attempt, counting from 1.
+func (policy RetryPolicy) attemptContext(attempt *http.Request, n int) (*http.Request, context.CancelFunc) {
+	deadline, ok := attempt.Context().Deadline()
+	if !policy.SplitDeadline || !ok {
+		return attempt, func() {}
+	}
+	share := time.Until(deadline) / time.Duration(policy.MaxAttempts-n+1)
+	ctx, cancel := context.WithTimeout(attempt.Context(), share)
+	return attempt.WithContext(ctx), cancel
+}
+
+// giveUp ends a retried call with the last attempt's response or error.
+// cancel is released with the response body, or at once if there is none.
+func giveUp(res *http.Response, err error, retryErr *RetryError, cancel context.CancelFunc) (*http.Response, error) {
+	if err != nil {
+		cancel()
+		if retryErr.Attempts > 1 {
+			retryErr.Err = err
+			return nil, retryErr
+		}
+		return nil, err
+	}
+	res.Body = &closeHook{ReadCloser: res.Body, hook: cancel}
+	return res, nil
+}
+
+// retryable reports whether an attempt that produced res and err may be
+// repeated for a request with the given method. err is either the error
+// from sending the request or from reading its response body.
+func retryable(method string, res *http.Response, err error) bool {
+	if err != nil {
+		return idempotent(method) && transient(err)
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return res.StatusCode >= 500 && idempotent(method)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// transient reports whether err is a dropped or reset connection, which a
+// fresh attempt may not hit
+func transient(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// bufferBody reads the body of res into memory, so that a connection
+// dropped mid-response can be retried before Do returns. On a read error the
+// body still yields the bytes read, followed by the error.
+func bufferBody(res *http.Response) error {
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		res.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(b), &errReader{err}))
+		return err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return nil
+}
+
+// errReader fails every read with err
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// rewind returns a copy of req with a fresh body, ready to be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return next, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	next.Body = body
+	return next, nil
+}
+
+// backoff returns the jittered delay before retrying after attempt n
+func (policy RetryPolicy) backoff(n int) time.Duration {
+	if policy.BaseDelay <= 0 {
+		return 0
+	}
+	d := policy.BaseDelay
+	for i := 1; i < n && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses the Retry-After header of res, given either in seconds
+// or as an HTTP date
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(clock.Now()); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sleep waits for d, returning early with the context's error once ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	c, stop := clock.NewTimer(d)
+	defer stop()
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,565 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/edgegrid"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// withRetry installs policy as Retry for the duration of the test
+func withRetry(t *testing.T, policy RetryPolicy) {
+	Retry = policy
+	t.Cleanup(func() { Retry = RetryPolicy{} })
+}
+
+// fakeClock is a clock that only moves when advanced
+type fakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// withFakeClock installs a fakeClock for the duration of the test
+func withFakeClock(t *testing.T) *fakeClock {
+	fake := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	clock = fake
+	t.Cleanup(func() { clock = realClock{} })
+	return fake
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	c := make(chan time.Time, 1)
+	f.timers = append(f.timers, fakeTimer{at: f.now.Add(d), c: c})
+	return c, func() bool { return true }
+}
+
+// advance moves the clock forward by d, firing the timers now due
+func (f *fakeClock) advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = f.now.Add(d)
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if timer.at.After(f.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- f.now
+	}
+	f.timers = pending
+}
+
+// waitForTimers blocks until n timers are waiting to fire, failing the test
+// if they are not set within a few seconds
+func (f *fakeClock) waitForTimers(t *testing.T, n int) {
+	var waiting int
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		f.lock.Lock()
+		waiting = len(f.timers)
+		f.lock.Unlock()
+		if waiting >= n {
+			return
+		}
+	}
+	t.Fatalf("%d timers set, want %d", waiting, n)
+}
+
+// statusSequence replies with each status in turn, then 200 OK, counting
+// requests in calls
+func statusSequence(calls *int32, statuses ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte(`{}`))
+	}
+}
+
+func TestDoRetryIdempotent(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 503, 429)))
+	withRetry(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 503, 503, 503)))
+	withRetry(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryNonIdempotentOnlyOn429(t *testing.T) {
+	var calls int32
+	var bodies []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		statusSequence(&calls, 429, 503)(w, r)
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond})
+
+	req, _ := NewJSONRequest(config, "PUT", "/appsec/v1/configs/1/versions/2/selected-hostnames", map[string]string{"hostname": "www.example.com"})
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, []string{`{"hostname":"www.example.com"}`, `{"hostname":"www.example.com"}`}, bodies)
+}
+
+func TestDoRetryWithoutReplayableBody(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 429)))
+	withRetry(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	req, _ := NewRequest(config, "POST", "/papi/v1/cpcodes", ioutil.NopCloser(bytes.NewBufferString(`{}`)))
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryHonorsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+}
+
+func TestDoRetryMaxRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, MaxRetryAfter: time.Minute})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWithMaxRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxRetryAfter: time.Minute}
+	assert.Equal(t, time.Minute, policy.maxRetryAfter(context.Background()))
+	assert.Equal(t, time.Second, policy.maxRetryAfter(WithMaxRetryAfter(context.Background(), time.Second)))
+	assert.Equal(t, time.Duration(0), policy.maxRetryAfter(WithMaxRetryAfter(context.Background(), 0)))
+}
+
+func TestDoRetryStopsWhenContextDone(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 503)))
+	withRetry(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	_, err := Do(config, req.WithContext(ctx))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetrySplitDeadline(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, SplitDeadline: true})
+
+	// The stalled first attempt gets half the budget, leaving time to retry
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req.WithContext(ctx))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetrySplitDeadlineSkipsRetry(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 503)))
+	withRetry(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, SplitDeadline: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req.WithContext(ctx))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryThrottledThenTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: math.MaxInt32})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	_, err := Do(config, req.WithContext(ctx))
+
+	var retryErr *RetryError
+	if assert.True(t, errors.As(err, &retryErr)) {
+		assert.Equal(t, http.StatusTooManyRequests, retryErr.LastStatus)
+		assert.True(t, retryErr.Throttled > 0)
+		assert.True(t, retryErr.Attempts >= retryErr.Throttled)
+		assert.Contains(t, err.Error(), "throttled with 429")
+	}
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestDoRetryTruncatedBody(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `{"groups":{"items":[]}}`
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Write([]byte(body[:10]))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		data := map[string]interface{}{}
+		assert.NoError(t, BodyJSON(res, &data))
+		assert.Contains(t, data, "groups")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryTruncatedBodyOnLastAttempt(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`{"groups":`))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		err = BodyJSON(res, &map[string]interface{}{})
+		var decodeErr *DecodeError
+		if assert.True(t, errors.As(err, &decodeErr)) {
+			assert.True(t, decodeErr.Truncated)
+			assert.Equal(t, 10, decodeErr.BytesRead)
+		}
+	}
+}
+
+func TestDoRetryDroppedConnection(t *testing.T) {
+	var calls int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryKeepsRequestID(t *testing.T) {
+	var calls int32
+	var ids []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Request-ID"))
+		w.Header().Set("X-Trace-Id", "akamai-"+strconv.Itoa(len(ids)))
+		statusSequence(&calls, 503)(w, r)
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	edgegrid.SetupLogging()
+	hook := logtest.NewLocal(edgegrid.EdgegridLog)
+	level := edgegrid.EdgegridLog.GetLevel()
+	edgegrid.EdgegridLog.SetLevel(logrus.DebugLevel)
+	defer edgegrid.EdgegridLog.SetLevel(level)
+
+	var operations int
+	RequestIDFunc = func() string {
+		operations++
+		return "op-" + strconv.Itoa(operations)
+	}
+	defer func() { RequestIDFunc = nil }()
+
+	for i := 0; i < 2; i++ {
+		req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+		if res, err := Do(config, req); assert.NoError(t, err) {
+			res.Body.Close()
+		}
+	}
+	assert.Equal(t, []string{"op-1", "op-1", "op-2"}, ids)
+
+	var mapped []string
+	for _, entry := range hook.AllEntries() {
+		if strings.HasPrefix(entry.Message, "Request ID ") {
+			mapped = append(mapped, entry.Message)
+		}
+	}
+	if assert.Len(t, mapped, 3) {
+		assert.Equal(t, "Request ID op-1: GET /papi/v1/groups returned 503 Service Unavailable, Akamai request ID 'akamai-1'", mapped[0])
+		assert.Contains(t, mapped[1], "op-1")
+		assert.Contains(t, mapped[1], "akamai-2")
+		assert.Contains(t, mapped[2], "op-2")
+		assert.Contains(t, mapped[2], "akamai-3")
+	}
+}
+
+func TestDoKeepsCallerRequestID(t *testing.T) {
+	var id string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = r.Header.Get("X-Request-ID")
+	}))
+	config := startTestServer(t, server)
+	RequestIDFunc = func() string { return "generated" }
+	defer func() { RequestIDFunc = nil }()
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	req.Header.Set("X-Request-ID", "caller")
+	if res, err := Do(config, req); assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	assert.Equal(t, "caller", id)
+}
+
+func TestDoRetryPauseOnThrottle(t *testing.T) {
+	var calls int32
+	var arrivals int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/throttled" && atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		atomic.AddInt32(&arrivals, 1)
+		w.Write([]byte(`{}`))
+	}))
+	config := startTestServer(t, server)
+	withRetry(t, RetryPolicy{MaxAttempts: 2, PauseOnThrottle: true})
+	fake := withFakeClock(t)
+	defer func() { throttledUntil = time.Time{} }()
+
+	var wg sync.WaitGroup
+	do := func(path string) {
+		defer wg.Done()
+		req, _ := NewRequest(config, "GET", path, nil)
+		if res, err := Do(config, req); assert.NoError(t, err) {
+			res.Body.Close()
+		}
+	}
+	wg.Add(2)
+	go do("/throttled")
+	fake.waitForTimers(t, 1)
+	go do("/other")
+	fake.waitForTimers(t, 2)
+
+	// Both the retry and the unrelated call wait out the Retry-After
+	assert.Equal(t, int32(0), atomic.LoadInt32(&arrivals))
+	fake.advance(time.Minute)
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&arrivals))
+}
+
+func TestDoRetryOnRetrySequence(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 503, 429)))
+
+	var sequences [][]Attempt
+	withRetry(t, RetryPolicy{
+		MaxAttempts:     3,
+		BaseDelay:       time.Millisecond,
+		OnRetrySequence: func(attempts []Attempt) { sequences = append(sequences, attempts) },
+	})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err := Do(config, req)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	req, _ = NewRequest(config, "GET", "/papi/v1/groups", nil)
+	res, err = Do(config, req)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+
+	if assert.Len(t, sequences, 2) {
+		attempts := sequences[0]
+		if assert.Len(t, attempts, 3) {
+			assert.Equal(t, []int{503, 429, 200}, []int{attempts[0].StatusCode, attempts[1].StatusCode, attempts[2].StatusCode})
+			assert.True(t, attempts[0].Delay > 0)
+			assert.True(t, attempts[1].Delay > 0)
+			assert.Equal(t, time.Duration(0), attempts[2].Delay)
+			assert.NoError(t, attempts[2].Err)
+		}
+		assert.Equal(t, []Attempt{{StatusCode: 200}}, sequences[1])
+	}
+}
+
+func TestDoOnRetrySequenceWithoutRetries(t *testing.T) {
+	var calls int32
+	config := startTestServer(t, httptest.NewUnstartedServer(statusSequence(&calls, 503)))
+
+	var sequences [][]Attempt
+	withRetry(t, RetryPolicy{
+		MaxAttempts:     1,
+		OnRetrySequence: func(attempts []Attempt) { sequences = append(sequences, attempts) },
+	})
+
+	req, _ := NewRequest(config, "GET", "/papi/v1/groups", nil)
+	if res, err := Do(config, req); assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	Client = &http.Client{Transport: &http.Transport{
+		DialContext: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("dial failed")
+		},
+	}}
+	req, _ = NewRequest(config, "GET", "/papi/v1/groups", nil)
+	_, err := Do(config, req)
+	assert.Error(t, err)
+
+	if assert.Len(t, sequences, 2) {
+		assert.Equal(t, []Attempt{{StatusCode: 503}}, sequences[0])
+		if assert.Len(t, sequences[1], 1) {
+			assert.Equal(t, 0, sequences[1][0].StatusCode)
+			assert.Equal(t, err, sequences[1][0].Err)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := func(v string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": []string{v}}}
+	}
+
+	d, ok := retryAfter(header("120"))
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	fake := withFakeClock(t)
+	d, ok = retryAfter(header(fake.Now().Add(time.Hour).Format(http.TimeFormat)))
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, d)
+
+	_, ok = retryAfter(header("soon"))
+	assert.False(t, ok)
+	_, ok = retryAfter(&http.Response{Header: http.Header{}})
+	assert.False(t, ok)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond}
+	for n, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 20: maxBackoff} {
+		d := policy.backoff(n)
+		assert.True(t, d >= max/2 && d <= max, "attempt %d: %s", n, d)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(1))
+}
@@ -76,6 +76,7 @@ func GetZone(hostname string) (*Zone, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	if client.IsError(res) && res.StatusCode != 404 {
 		return nil, client.NewAPIError(res)
@@ -140,6 +141,9 @@ func (zone *Zone) Save() error {
 		return &ZoneError{zoneName: zone.Zone.Name, apiErrorMessage: err.Detail, err: err}
 	}
 
+	// Release the connection before polling for the updated zone
+	client.DrainBody(res)
+
 	for {
 		updatedZone, err := GetZone(zone.Zone.Name)
 		if err != nil {
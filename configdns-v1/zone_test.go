@@ -1,8 +1,16 @@
 package dns
 
 import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/client-v1"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/edgegrid"
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/jsonhooks-v1"
 	"github.com/stretchr/testify/assert"
 )
@@ -335,3 +343,55 @@ func testZone_AddRecord_Provider() []*ARecord {
 		},
 	}
 }
+
+func TestGetZone_NotFoundReleasesConnection(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"title":"Not Found","status":404,"detail":"` + strings.Repeat("x", 8192) + `"}`))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	httpClient, savedConfig := client.Client, Config
+	client.Client = server.Client()
+	defer func() { client.Client, Config = httpClient, savedConfig }()
+	Init(edgegrid.Config{Host: server.URL, ClientToken: "token", ClientSecret: "secret", AccessToken: "token"})
+
+	for i := 0; i < 3; i++ {
+		_, err := GetZone("example.com")
+		assert.Equal(t, &ZoneError{zoneName: "example.com"}, err)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
+
+func TestGetZone_NotFoundUnderMaxConcurrency(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"title":"Not Found","status":404}`))
+	}))
+	defer server.Close()
+
+	httpClient, savedConfig, timeout := client.Client, Config, client.DefaultTimeout
+	client.Client = server.Client()
+	client.DefaultTimeout = time.Second
+	client.SetMaxConcurrency(1)
+	defer func() {
+		client.Client, Config, client.DefaultTimeout = httpClient, savedConfig, timeout
+		client.SetMaxConcurrency(0)
+	}()
+	Init(edgegrid.Config{Host: server.URL, ClientToken: "token", ClientSecret: "secret", AccessToken: "token"})
+
+	// A leaked body would hold the only slot, and the next call would time out
+	for i := 0; i < 3; i++ {
+		_, err := GetZone("example.com")
+		assert.Equal(t, &ZoneError{zoneName: "example.com"}, err)
+	}
+}
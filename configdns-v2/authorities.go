@@ -36,6 +36,7 @@ func GetAuthorities(contractId string) (*AuthorityResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
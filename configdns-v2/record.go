@@ -119,6 +119,7 @@ func (record *RecordBody) Save(zone string, recLock ...bool) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -165,6 +166,7 @@ func (record *RecordBody) Update(zone string, recLock ...bool) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -211,6 +213,7 @@ func (record *RecordBody) Delete(zone string, recLock ...bool) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -100,6 +100,7 @@ func GetRecord(zone string, name string, record_type string) (*RecordBody, error
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -136,6 +137,7 @@ func GetRecordList(zone string, name string, record_type string) (*RecordSetResp
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
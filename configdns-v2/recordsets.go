@@ -101,6 +101,7 @@ func GetRecordsets(zone string, queryArgs ...RecordsetQueryArgs) (*RecordSetResp
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -152,6 +153,7 @@ func (recordsets *Recordsets) Save(zone string, recLock ...bool) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -198,6 +200,7 @@ func (recordsets *Recordsets) Update(zone string, recLock ...bool) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -184,6 +184,7 @@ func (tsigKey *TSIGKey) GetZones() (*ZoneNameListResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -228,6 +229,7 @@ func GetZoneKeyAliases(zone string) (*ZoneNameListResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -274,6 +276,7 @@ func (tsigBulk *TSIGKeyBulkPost) BulkUpdate() error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	// API error
 	if client.IsError(res) {
@@ -305,6 +308,7 @@ func GetZoneKey(zone string) (*TSIGKeyResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -347,6 +351,7 @@ func DeleteZoneKey(zone string) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -384,6 +389,7 @@ func (tsigKey *TSIGKey) Update(zone string) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -269,6 +269,7 @@ func GetZone(zonename string) (*ZoneResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -305,6 +306,7 @@ func GetChangeList(zone string) (*ChangeListResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -343,6 +345,7 @@ func GetMasterZoneFile(zone string) (string, error) {
 		log.Printf("[DEBUG] [Akamai LIB] ZM %v %v", res, err)
 		return "", err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -389,6 +392,7 @@ func PostMasterZoneFile(zone string, filedata string) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -438,6 +442,7 @@ func (zone *ZoneCreate) Save(zonequerystring ZoneQueryString, clearConn ...bool)
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -491,6 +496,7 @@ func (zone *ZoneCreate) SaveChangelist() error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -533,6 +539,7 @@ func (zone *ZoneCreate) SubmitChangelist() error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -576,6 +583,7 @@ func (zone *ZoneCreate) Update(zonequerystring ZoneQueryString) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -614,6 +622,7 @@ func (zone *ZoneCreate) Delete(zonequerystring ZoneQueryString) error {
 			err:              err,
 		}
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -728,6 +737,7 @@ func GetZoneNames(zone string) (*ZoneNamesResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -765,6 +775,7 @@ func GetZoneNameTypes(zname string, zone string) (*ZoneNameTypesResponse, error)
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -52,6 +52,7 @@ func GetAsMap(name, domainName string) (*AsMap, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
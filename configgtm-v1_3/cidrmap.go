@@ -57,6 +57,7 @@ func ListCidrMaps(domainName string) ([]*CidrMap, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -95,6 +96,7 @@ func GetCidrMap(name, domainName string) (*CidrMap, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
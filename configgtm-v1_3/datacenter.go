@@ -72,6 +72,7 @@ func ListDatacenters(domainName string) ([]*Datacenter, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -111,6 +112,7 @@ func GetDatacenter(dcID int, domainName string) (*Datacenter, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpRequest(req, true)
 
@@ -98,6 +98,7 @@ func GetDomainStatus(domainName string) (*ResponseStatus, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -136,6 +137,7 @@ func ListDomains() ([]*DomainItem, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -174,6 +176,7 @@ func GetDomain(domainName string) (*Domain, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -143,6 +143,7 @@ func ListProperties(domainName string) ([]*Property, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -181,6 +182,7 @@ func GetProperty(name, domainName string) (*Property, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -75,6 +75,7 @@ func ListResources(domainName string) ([]*Resource, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -113,6 +114,7 @@ func GetResource(name, domainName string) (*Resource, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -74,6 +74,7 @@ func ListDatacenters(domainName string) ([]*Datacenter, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -113,6 +114,7 @@ func GetDatacenter(dcID int, domainName string) (*Datacenter, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpRequest(req, true)
 
@@ -101,6 +101,7 @@ func GetDomainStatus(domainName string) (*ResponseStatus, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -139,6 +140,7 @@ func ListDomains() ([]*DomainItem, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -177,6 +179,7 @@ func GetDomain(domainName string) (*Domain, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -377,6 +380,8 @@ func (domain *Domain) NullFieldMap() (*NullFieldMapStruct, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
+
 	printHttpResponse(res, true)
 	if client.IsError(res) && res.StatusCode != 404 {
 		return nil, client.NewAPIError(res)
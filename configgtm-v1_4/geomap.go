@@ -57,6 +57,7 @@ func ListGeoMaps(domainName string) ([]*GeoMap, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -96,6 +97,7 @@ func GetGeoMap(name, domainName string) (*GeoMap, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -159,6 +159,7 @@ func ListProperties(domainName string) ([]*Property, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -197,6 +198,7 @@ func GetProperty(name, domainName string) (*Property, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
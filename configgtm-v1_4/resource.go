@@ -74,6 +74,7 @@ func ListResources(domainName string) ([]*Resource, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -112,6 +113,7 @@ func GetResource(name, domainName string) (*Resource, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 
@@ -2,6 +2,7 @@ package edgegrid
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -110,15 +111,94 @@ func InitEdgeRc(filepath string, section string) (Config, error) {
 		return c, fmt.Errorf(errorMap[ErrConfigMissingOptions], missing)
 	}
 	if c.MaxBody == 0 {
-		c.MaxBody = 131072
+		c.MaxBody = defaultMaxBody
 	}
 	return c, nil
 }
 
+// SectionValidation holds the result of validating a single .edgerc section
+type SectionValidation struct {
+	Section string
+	Missing []string
+	Errors  []error
+}
+
+// Valid reports whether the section holds all the options required to sign requests
+func (v SectionValidation) Valid() bool {
+	return len(v.Missing) == 0 && len(v.Errors) == 0
+}
+
+// ValidateEdgerc parses every section of a configuration file in standard INI
+// format and reports, per section, whether the required options are present
+// and the host is well-formed. No network calls are made.
+//
+// By default, it uses the .edgerc found in the users home directory.
+func ValidateEdgerc(filepath string) ([]SectionValidation, error) {
+	var (
+		results         []SectionValidation
+		requiredOptions = []string{"host", "client_token", "client_secret", "access_token"}
+	)
+
+	if filepath == "" {
+		filepath = "~/.edgerc"
+	}
+
+	path, err := homedir.Expand(filepath)
+	if err != nil {
+		return nil, fmt.Errorf(errorMap[ErrHomeDirNotFound], err)
+	}
+
+	edgerc, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf(errorMap[ErrConfigFile], err)
+	}
+
+	for _, section := range edgerc.Sections() {
+		// The unnamed section only shows up when keys precede the first header
+		if section.Name() == ini.DefaultSection && len(section.Keys()) == 0 {
+			continue
+		}
+
+		result := SectionValidation{Section: section.Name()}
+		for _, opt := range requiredOptions {
+			if !section.HasKey(opt) || strings.TrimSpace(section.Key(opt).String()) == "" {
+				result.Missing = append(result.Missing, opt)
+			}
+		}
+
+		var c Config
+		if err := section.MapTo(&c); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf(errorMap[ErrConfigFileSection], err))
+		} else if c.Host != "" {
+			if err := validateHost(c.Host); err != nil {
+				result.Errors = append(result.Errors, err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// validateHost checks host is a bare hostname, optionally prefixed with
+// https:// and followed by a trailing slash, as accepted by client.NewRequest
+func validateHost(host string) error {
+	u, err := url.Parse("https://" + strings.TrimPrefix(host, "https://"))
+	if err != nil {
+		return fmt.Errorf(errorMap[ErrConfigInvalidHost], err)
+	}
+	if u.Hostname() == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return fmt.Errorf(errorMap[ErrConfigInvalidHost], host)
+	}
+	return nil
+}
+
 // InitEnv initializes using the Environment (ENV)
 //
 // By default, it uses AKAMAI_HOST, AKAMAI_CLIENT_TOKEN, AKAMAI_CLIENT_SECRET,
-// AKAMAI_ACCESS_TOKEN, and AKAMAI_MAX_BODY variables.
+// AKAMAI_ACCESS_TOKEN, AKAMAI_ACCOUNT_KEY, and AKAMAI_MAX_BODY variables.
+// AKAMAI_ACCOUNT_KEY and AKAMAI_MAX_BODY are optional.
 //
 // You can define multiple configurations by prefixing with the section name specified, e.g.
 // passing "ccu" will cause it to look for AKAMAI_CCU_HOST, etc.
@@ -167,6 +247,8 @@ func InitEnv(section string) (Config, error) {
 		return c, fmt.Errorf(errorMap[ErrMissingEnvVariables], missing)
 	}
 
+	c.AccountKey = os.Getenv(prefix + "ACCOUNT_KEY")
+
 	c.MaxBody = 0
 
 	val, ok := os.LookupEnv(prefix + "MAX_BODY")
@@ -175,7 +257,7 @@ func InitEnv(section string) (Config, error) {
 	}
 
 	if !ok || c.MaxBody == 0 {
-		c.MaxBody = 131072
+		c.MaxBody = defaultMaxBody
 	}
 
 	return c, nil
@@ -127,6 +127,28 @@ func TestInitEnv_MaxBody(t *testing.T) {
 	assert.Equal(t, c.HeaderToSign, []string(nil))
 }
 
+func TestInitEnv_AccountKey(t *testing.T) {
+	os.Clearenv()
+	err := os.Setenv("AKAMAI_TEST_HOST", "env-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx.luna.akamaiapis.net/")
+	assert.NoError(t, err)
+	err = os.Setenv("AKAMAI_TEST_CLIENT_TOKEN", "env-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx")
+	assert.NoError(t, err)
+	err = os.Setenv("AKAMAI_TEST_CLIENT_SECRET", "envxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx=")
+	assert.NoError(t, err)
+	err = os.Setenv("AKAMAI_TEST_ACCESS_TOKEN", "env-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx")
+	assert.NoError(t, err)
+	err = os.Setenv("AKAMAI_TEST_ACCOUNT_KEY", "1-ABCDE")
+	assert.NoError(t, err)
+
+	c, err := InitEnv("test")
+	assert.NoError(t, err)
+	assert.Equal(t, c.AccountKey, "1-ABCDE")
+
+	c, err = Init("", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, c.AccountKey, "1-ABCDE")
+}
+
 func TestInit_WithEnv(t *testing.T) {
 	os.Clearenv()
 	err := os.Setenv("AKAMAI_HOST", "env-xxxxxxxxxxxxxxxx-xxxxxxxxxxxxxxxx.luna.akamaiapis.net/")
@@ -193,3 +215,29 @@ func TestInitEdgeRc_NoDefault(t *testing.T) {
 	assert.Equal(t, c.MaxBody, 131072)
 	assert.Equal(t, c.HeaderToSign, []string(nil))
 }
+
+func TestValidateEdgerc(t *testing.T) {
+	results, err := ValidateEdgerc("../testdata/validate_edgerc")
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+
+	assert.Equal(t, "default", results[0].Section)
+	assert.True(t, results[0].Valid())
+
+	assert.Equal(t, "prefixed", results[1].Section)
+	assert.True(t, results[1].Valid())
+
+	assert.Equal(t, "badhost", results[2].Section)
+	assert.False(t, results[2].Valid())
+	assert.Empty(t, results[2].Missing)
+	assert.Len(t, results[2].Errors, 1)
+
+	assert.Equal(t, "dashes", results[3].Section)
+	assert.False(t, results[3].Valid())
+	assert.Equal(t, []string{"client_token", "client_secret", "access_token"}, results[3].Missing)
+}
+
+func TestValidateEdgerc_ConfigNotFound(t *testing.T) {
+	_, err := ValidateEdgerc("edgerc_not_found")
+	assert.Error(t, err)
+}
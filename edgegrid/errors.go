@@ -8,6 +8,7 @@ const (
 	ErrConfigFileSection    = 503
 	ErrConfigMissingOptions = 504
 	ErrMissingEnvVariables  = 505
+	ErrConfigInvalidHost    = 506
 )
 
 var (
@@ -18,5 +19,6 @@ var (
 		ErrConfigFileSection:    "Could not map section: %s",
 		ErrConfigMissingOptions: "Fatal missing required options: %s",
 		ErrMissingEnvVariables:  "Fatal missing required environment variables: %s",
+		ErrConfigInvalidHost:    "Invalid host: %s",
 	}
 )
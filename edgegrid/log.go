@@ -19,9 +19,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 
 	logstd "log"
@@ -87,13 +90,87 @@ func LogMultilinef(f func(formatter string, args ...interface{}), formatter stri
 	}
 }
 
+// RedactedHeaders lists the request headers whose values are replaced with
+// "***" when requests are logged. Append to it to redact additional headers.
+var RedactedHeaders = []string{"Authorization"}
+
+// LogBodies, when set, lets AddRequestHeader log the request bodies it signs
+// at debug level. Bodies may carry secrets, so it is off by default.
+var LogBodies bool
+
+// redactedQueryParams lists query parameters redacted when requests are logged
+var redactedQueryParams = []string{"accountSwitchKey"}
+
+func isRedactedHeader(name string) bool {
+	for _, h := range RedactedHeaders {
+		if http.CanonicalHeaderKey(h) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedRequest returns a copy of req that is safe to log. When body is
+// true the body is read and restored on both req and the copy.
+func redactedRequest(req *http.Request, body bool) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if body && req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	for k := range r.Header {
+		if isRedactedHeader(k) {
+			r.Header.Set(k, "***")
+		}
+	}
+
+	r.URL.RawQuery = redactQuery(r.URL.RawQuery)
+	return r, nil
+}
+
+// redactQuery replaces the values of redactedQueryParams in rawQuery with a
+// literal *** and leaves every other parameter as it was sent
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		rawKey := strings.SplitN(pair, "=", 2)[0]
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		for _, p := range redactedQueryParams {
+			if key == p {
+				pairs[i] = rawKey + "=***"
+			}
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// dumpRequest renders req for logging with sensitive values redacted
+func dumpRequest(req *http.Request, body bool) ([]byte, error) {
+	r, err := redactedRequest(req, body)
+	if err != nil {
+		return nil, err
+	}
+	return httputil.DumpRequestOut(r, body)
+}
+
 // Utility func to print http req
 func PrintHttpRequest(req *http.Request, body bool) {
 
 	if req == nil {
 		return
 	}
-	b, err := httputil.DumpRequestOut(req, body)
+	b, err := dumpRequest(req, body)
 	if err == nil {
 		LogMultiline(EdgegridLog.Traceln, string(b))
 	}
@@ -104,13 +181,73 @@ func PrintHttpRequestCorrelation(req *http.Request, body bool, correlationid str
 	if req == nil {
 		return
 	}
-	b, err := httputil.DumpRequestOut(req, body)
+	b, err := dumpRequest(req, body)
 	if err == nil {
 		LogMultiline(EdgegridLog.Traceln, string(b))
 		PrintfCorrelation("[DEBUG] REQUEST", correlationid, prettyPrintJsonLines(b))
 	}
 }
 
+// CurlPlaceholderAuthorization replaces the Authorization header value in
+// commands produced by CurlCommand
+const CurlPlaceholderAuthorization = "<EG1-HMAC-SHA256 signature redacted>"
+
+// CurlCommand renders req as an equivalent curl command line, for
+// reproducing API calls outside of Go. The Authorization header is always
+// replaced with CurlPlaceholderAuthorization, and other RedactedHeaders and
+// the accountSwitchKey query parameter with ***. The request body, if any,
+// is read and restored so req can still be sent.
+func CurlCommand(req *http.Request) (string, error) {
+	u := *req.URL
+	u.RawQuery = redactQuery(u.RawQuery)
+	parts := []string{"curl", "-X", req.Method, shellQuote(u.String())}
+
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			if http.CanonicalHeaderKey(k) == "Authorization" {
+				v = CurlPlaceholderAuthorization
+			} else if isRedactedHeader(k) {
+				v = "***"
+			}
+			parts = append(parts, "-H", shellQuote(k+": "+v))
+		}
+	}
+
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewBuffer(b))
+		if len(b) > 0 {
+			parts = append(parts, "--data-binary", shellQuote(string(b)))
+		}
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// Utility func to print http req as a curl command
+func PrintCurlRequest(req *http.Request) {
+
+	if req == nil {
+		return
+	}
+	cmd, err := CurlCommand(req)
+	if err == nil {
+		EdgegridLog.Traceln(cmd)
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
 // Utility func to print http response
 func PrintHttpResponse(res *http.Response, body bool) {
 
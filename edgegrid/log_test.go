@@ -0,0 +1,81 @@
+package edgegrid
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurlCommand(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "https://akaa-baseurl.luna.akamaiapis.net/papi/v1/cpcodes?contractId=ctr_1", bytes.NewBufferString(`{"name":"it's"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = AddRequestHeader(config, req)
+
+	actual, err := CurlCommand(req)
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -X PUT 'https://akaa-baseurl.luna.akamaiapis.net/papi/v1/cpcodes?contractId=ctr_1' `+
+		`-H 'Authorization: <EG1-HMAC-SHA256 signature redacted>' `+
+		`-H 'Content-Type: application/json' `+
+		`--data-binary '{"name":"it'\''s"}'`, actual)
+	assert.NotContains(t, actual, config.ClientToken)
+
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, `{"name":"it's"}`, string(body))
+}
+
+func TestCurlCommand_Redacted(t *testing.T) {
+	RedactedHeaders = append(RedactedHeaders, "X-Api-Key")
+	defer func() { RedactedHeaders = RedactedHeaders[:len(RedactedHeaders)-1] }()
+
+	req, _ := http.NewRequest("GET", "https://akaa-baseurl.luna.akamaiapis.net/papi/v1/groups?accountSwitchKey=1-ABCDE&contractId=ctr_1", nil)
+	req.Header.Set("X-Api-Key", "secret-api-key")
+	req = AddRequestHeader(config, req)
+
+	actual, err := CurlCommand(req)
+	assert.NoError(t, err)
+	assert.Contains(t, actual, `'https://akaa-baseurl.luna.akamaiapis.net/papi/v1/groups?accountSwitchKey=***&contractId=ctr_1'`)
+	assert.Contains(t, actual, `-H 'X-Api-Key: ***'`)
+	assert.NotContains(t, actual, "1-ABCDE")
+	assert.NotContains(t, actual, "secret-api-key")
+	assert.Equal(t, "accountSwitchKey=1-ABCDE&contractId=ctr_1", req.URL.RawQuery)
+}
+
+func TestPrintHttpRequest_Redacted(t *testing.T) {
+	SetupLogging()
+	hook := logtest.NewLocal(EdgegridLog)
+	level := EdgegridLog.GetLevel()
+	EdgegridLog.SetLevel(logrus.TraceLevel)
+	defer EdgegridLog.SetLevel(level)
+
+	RedactedHeaders = append(RedactedHeaders, "X-Api-Key")
+	defer func() { RedactedHeaders = RedactedHeaders[:len(RedactedHeaders)-1] }()
+
+	payload := `{"hostnameList":[{"hostname":"www.example.com"}]}`
+	req, _ := http.NewRequest("PUT", "https://akaa-baseurl.luna.akamaiapis.net/config?accountSwitchKey=1-ABCDE", bytes.NewBufferString(payload))
+	req.Header.Set("X-Api-Key", "secret-api-key")
+	req = AddRequestHeader(config, req)
+	hook.Reset()
+
+	PrintHttpRequest(req, true)
+
+	var logged string
+	for _, entry := range hook.AllEntries() {
+		logged += entry.Message + "\n"
+	}
+	assert.Contains(t, logged, "www.example.com")
+	assert.Contains(t, logged, "Authorization: ***")
+	assert.Contains(t, logged, "X-Api-Key: ***")
+	assert.Contains(t, logged, "/config?accountSwitchKey=*** HTTP/1.1")
+	assert.NotContains(t, logged, "EG1-HMAC-SHA256")
+	assert.NotContains(t, logged, "secret-api-key")
+	assert.NotContains(t, logged, "1-ABCDE")
+
+	assert.Contains(t, req.Header.Get("Authorization"), "EG1-HMAC-SHA256")
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, payload, string(body))
+}
@@ -19,7 +19,24 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const defaultSection = "DEFAULT"
+const (
+	defaultSection = "DEFAULT"
+	// defaultMaxBody is the EdgeGrid default for max_body, in bytes
+	defaultMaxBody = 131072
+)
+
+// NonceStore is notified of the nonce and timestamp of every request signed
+// by AddRequestHeader, so that security tooling can audit nonce uniqueness.
+// Record is called from every goroutine that signs a request, including
+// when client.Do re-signs a redirect, so implementations must be safe for
+// concurrent use.
+type NonceStore interface {
+	Record(nonce, timestamp string)
+}
+
+// Nonces, when set, records every nonce issued by AddRequestHeader. It is
+// nil by default, keeping signing stateless.
+var Nonces NonceStore
 
 // AddRequestHeader sets the Authorization header to use Akamai Open API
 func AddRequestHeader(config Config, req *http.Request) *http.Request {
@@ -30,10 +47,14 @@ func AddRequestHeader(config Config, req *http.Request) *http.Request {
 			EdgegridLog.SetLevel(logrus.DebugLevel)
 		}
 	}
-	timestamp := makeEdgeTimeStamp()
-	EdgegridLog.Debugf("Timestamp: '%s'", timestamp)
+	now := time.Now()
+	timestamp := formatEdgeTimeStamp(now)
+	EdgegridLog.Debugf("Timestamp: '%s' (local time %s)", timestamp, now.Format(time.RFC3339))
 	nonce := createNonce()
 	EdgegridLog.Debugf("Nonce: '%s'", nonce)
+	if Nonces != nil {
+		Nonces.Record(nonce, timestamp)
+	}
 
 	if req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/json")
@@ -63,13 +84,19 @@ func AddRequestHeader(config Config, req *http.Request) *http.Request {
 	return req
 }
 
+// edgeTimeStampFormat is the layout of “yyyyMMddTHH:mm:ss+0000”. The offset is
+// a literal, so times must be converted to UTC before formatting.
+const edgeTimeStampFormat = "20060102T15:04:05+0000"
+
 // Must be assigned the UTC time when the request is signed.
 // Format of “yyyyMMddTHH:mm:ss+0000”
 func makeEdgeTimeStamp() string {
-	local := time.FixedZone("GMT", 0)
-	t := time.Now().In(local)
-	return fmt.Sprintf("%d%02d%02dT%02d:%02d:%02d+0000",
-		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
+	return formatEdgeTimeStamp(time.Now())
+}
+
+// formatEdgeTimeStamp renders t in UTC, whatever location it carries
+func formatEdgeTimeStamp(t time.Time) string {
+	return t.UTC().Format(edgeTimeStampFormat)
 }
 
 // Must be assigned a nonce (number used once) for the request.
@@ -164,14 +191,25 @@ func createContentHash(config Config, req *http.Request) string {
 		preparedBody = string(bodyBytes)
 	}
 
-	EdgegridLog.Debugf("Body is %s", preparedBody)
+	// A Config built by hand rather than through Init* may leave MaxBody
+	// unset, which would otherwise hash every body as empty
+	maxBody := config.MaxBody
+	if maxBody <= 0 {
+		maxBody = defaultMaxBody
+	}
+
+	if LogBodies {
+		EdgegridLog.Debugf("Body is %s", preparedBody)
+	}
 	if req.Method == "POST" && len(preparedBody) > 0 {
-		EdgegridLog.Debugf("Signing content: %s", preparedBody)
-		if len(preparedBody) > config.MaxBody {
+		if LogBodies {
+			EdgegridLog.Debugf("Signing content: %s", preparedBody)
+		}
+		if len(preparedBody) > maxBody {
 			EdgegridLog.Debugf("Data length %d is larger than maximum %d",
-				len(preparedBody), config.MaxBody)
+				len(preparedBody), maxBody)
 
-			preparedBody = preparedBody[0:config.MaxBody]
+			preparedBody = preparedBody[0:maxBody]
 			EdgegridLog.Debugf("Data truncated to %d for computing the hash", len(preparedBody))
 		}
 		contentHash = createHash(preparedBody)
@@ -194,10 +232,21 @@ func signingData(config Config, req *http.Request, authHeader string) string {
 		createContentHash(config, req),
 		authHeader,
 	}
-	EdgegridLog.Debugf("Data to sign %s", strings.Join(dataSign, "\t"))
+	logged := append([]string(nil), dataSign...)
+	logged[3] = concatPathQuery(req.URL.EscapedPath(), redactQuery(req.URL.RawQuery))
+	logged[6] = redactedAuthHeader(config, authHeader)
+	EdgegridLog.Debugf("Data to sign %s", strings.Join(logged, "\t"))
 	return strings.Join(dataSign, "\t")
 }
 
+// redactedAuthHeader masks the client and access tokens in authHeader
+func redactedAuthHeader(config Config, authHeader string) string {
+	return strings.NewReplacer(
+		"client_token="+config.ClientToken+";", "client_token=***;",
+		"access_token="+config.AccessToken+";", "access_token=***;",
+	).Replace(authHeader)
+}
+
 func signingRequest(config Config, req *http.Request, authHeader string, timestamp string) string {
 	return createSignature(signingData(config, req, authHeader),
 		signingKey(config, timestamp))
@@ -213,10 +262,11 @@ func createAuthHeader(config Config, req *http.Request, timestamp string, nonce
 		timestamp,
 		nonce,
 	)
-	EdgegridLog.Debugf("Unsigned authorization header: '%s'", authHeader)
+	EdgegridLog.Debugf("Unsigned authorization header: '%s'", redactedAuthHeader(config, authHeader))
 
 	signedAuthHeader := fmt.Sprintf("%ssignature=%s", authHeader, signingRequest(config, req, authHeader, timestamp))
 
-	EdgegridLog.Debugf("Signed authorization header: '%s'", signedAuthHeader)
+	// The signature itself is never logged
+	EdgegridLog.Debugf("Signed authorization header with timestamp '%s'", timestamp)
 	return signedAuthHeader
 }
@@ -7,9 +7,14 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/jsonhooks-v1"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,6 +59,21 @@ func TestMakeEdgeTimeStamp(t *testing.T) {
 	}
 }
 
+func TestFormatEdgeTimeStamp(t *testing.T) {
+	utc := time.Date(2014, time.March, 21, 19, 34, 21, 0, time.UTC)
+	tests := map[string]time.Time{
+		"UTC":            utc,
+		"UTC+9":          utc.In(time.FixedZone("JST", 9*60*60)),
+		"UTC-5:30":       utc.In(time.FixedZone("", -(5*60+30)*60)),
+		"across new day": utc.In(time.FixedZone("", 14*60*60)),
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, timestamp, formatEdgeTimeStamp(in))
+		})
+	}
+}
+
 func TestCreateNonce(t *testing.T) {
 	actual := createNonce()
 	for i := 0; i < 100; i++ {
@@ -97,3 +117,110 @@ func TestCreateAuthHeader(t *testing.T) {
 
 	}
 }
+
+func TestCreateContentHash_MaxBody(t *testing.T) {
+	SetupLogging()
+	body := strings.Repeat("x", defaultMaxBody+1)
+	tests := map[string]struct {
+		maxBody  int
+		expected string
+	}{
+		"unset max_body uses default": {0, createHash(body[:defaultMaxBody])},
+		"configured max_body":         {10, createHash(body[:10])},
+		"max_body above body size":    {defaultMaxBody * 2, createHash(body)},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "https://akaa-baseurl.luna.akamaiapis.net/", strings.NewReader(body))
+			c := Config{MaxBody: test.maxBody}
+			assert.Equal(t, test.expected, createContentHash(c, req))
+		})
+	}
+}
+
+func TestAddRequestHeader_LogsTimestampNotSignature(t *testing.T) {
+	SetupLogging()
+	hook := logtest.NewLocal(EdgegridLog)
+	level := EdgegridLog.GetLevel()
+	EdgegridLog.SetLevel(logrus.DebugLevel)
+	defer EdgegridLog.SetLevel(level)
+
+	body := `{"hostnameList":[{"hostname":"secret.example.com"}]}`
+	req, _ := http.NewRequest("POST", "https://akaa-baseurl.luna.akamaiapis.net/papi/v1/cpcodes?accountSwitchKey=1-ABCDE&contractId=ctr_1", strings.NewReader(body))
+	req = AddRequestHeader(config, req)
+
+	auth := req.Header.Get("Authorization")
+	signature := auth[strings.Index(auth, "signature=")+len("signature="):]
+	sawTimestamp := false
+	for _, entry := range hook.AllEntries() {
+		assert.NotContains(t, entry.Message, signature)
+		assert.NotContains(t, entry.Message, config.ClientSecret)
+		assert.NotContains(t, entry.Message, config.ClientToken)
+		assert.NotContains(t, entry.Message, config.AccessToken)
+		assert.NotContains(t, entry.Message, "1-ABCDE")
+		assert.NotContains(t, entry.Message, "secret.example.com")
+		if strings.HasPrefix(entry.Message, "Timestamp: ") && strings.Contains(entry.Message, "local time") {
+			sawTimestamp = true
+		}
+	}
+	assert.True(t, sawTimestamp)
+}
+
+func TestAddRequestHeader_LogBodies(t *testing.T) {
+	SetupLogging()
+	hook := logtest.NewLocal(EdgegridLog)
+	level := EdgegridLog.GetLevel()
+	EdgegridLog.SetLevel(logrus.DebugLevel)
+	defer EdgegridLog.SetLevel(level)
+
+	LogBodies = true
+	defer func() { LogBodies = false }()
+
+	req, _ := http.NewRequest("POST", "https://akaa-baseurl.luna.akamaiapis.net/papi/v1/cpcodes", strings.NewReader(`{"cpcodeName":"example"}`))
+	AddRequestHeader(config, req)
+
+	var logged string
+	for _, entry := range hook.AllEntries() {
+		logged += entry.Message + "\n"
+	}
+	assert.Contains(t, logged, `Body is {"cpcodeName":"example"}`)
+	assert.Contains(t, logged, "client_token=***;access_token=***;")
+}
+
+type recordedNonces struct {
+	sync.Mutex
+	nonces map[string]string
+}
+
+func (r *recordedNonces) Record(nonce, timestamp string) {
+	r.Lock()
+	defer r.Unlock()
+	r.nonces[nonce] = timestamp
+}
+
+func TestAddRequestHeader_NonceStore(t *testing.T) {
+	store := &recordedNonces{nonces: map[string]string{}}
+	Nonces = store
+	defer func() { Nonces = nil }()
+
+	signed := make(chan *http.Request, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "https://akaa-baseurl.luna.akamaiapis.net/", nil)
+			signed <- AddRequestHeader(config, req)
+		}()
+	}
+	wg.Wait()
+	close(signed)
+
+	for req := range signed {
+		auth := req.Header.Get("Authorization")
+		nonce := regexp.MustCompile(`nonce=([^;]+);`).FindStringSubmatch(auth)[1]
+		timestamp := regexp.MustCompile(`timestamp=([^;]+);`).FindStringSubmatch(auth)[1]
+		assert.Equal(t, timestamp, store.nonces[nonce])
+	}
+	assert.Len(t, store.nonces, 3)
+}
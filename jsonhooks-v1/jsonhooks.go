@@ -8,6 +8,9 @@ import (
 )
 
 // Marshal wraps encoding/json.Marshal, calls v.PreMarshalJSON() if it exists
+//
+// Output is byte-stable: struct fields keep their declaration order and map
+// keys are sorted, so request bodies can be recorded and compared across runs.
 func Marshal(v interface{}) ([]byte, error) {
 	if ImplementsPreJSONMarshaler(v) {
 		err := v.(PreJSONMarshaler).PreMarshalJSON()
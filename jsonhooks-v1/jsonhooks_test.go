@@ -208,3 +208,23 @@ func TestPostJSONUnmarshal(t *testing.T) {
 	assert.NotEqual(t, expected, withoutHooks)
 	assert.Equal(t, expected, withHooks)
 }
+
+func TestMarshalDeterministic(t *testing.T) {
+	o := Optionals{
+		Mr: map[string]interface{}{
+			"zulu":  1,
+			"alpha": map[string]interface{}{"y": true, "b": false},
+			"mike":  []string{"c", "a"},
+		},
+	}
+
+	expected, err := Marshal(o)
+	assert.NoError(t, err)
+	assert.Contains(t, string(expected), `"mr":{"alpha":{"b":false,"y":true},"mike":["c","a"],"zulu":1}`)
+
+	for i := 0; i < 50; i++ {
+		actual, err := Marshal(o)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(actual))
+	}
+}
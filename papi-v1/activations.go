@@ -252,6 +252,7 @@ func (activation *Activation) Save(property *Property, acknowledgeWarnings bool)
 	if err != nil {
 		return err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponse(res, true)
 
@@ -268,6 +269,7 @@ func (activation *Activation) Save(property *Property, acknowledgeWarnings bool)
 		}{}
 
 		body, err := ioutil.ReadAll(res.Body)
+		client.DrainBody(res)
 		if err != nil {
 			return err
 		}
@@ -182,6 +182,7 @@ func (behavior *AvailableBehavior) GetSchema() (*gojsonschema.Schema, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	schemaBytes, _ := ioutil.ReadAll(res.Body)
 	schemaBody := string(schemaBytes)
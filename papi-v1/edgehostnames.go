@@ -225,6 +225,8 @@ func (edgeHostname *EdgeHostname) GetEdgeHostname(options string, correlationid
 	edge.PrintHttpResponseCorrelation(res, true, correlationid)
 
 	if client.IsError(res) {
+		// Reading the error releases the connection before the lookup below
+		apiErr := client.NewAPIError(res)
 		if res.StatusCode == 404 {
 			// Check collection for current hostname
 			contract := NewContract(NewContracts())
@@ -235,7 +237,7 @@ func (edgeHostname *EdgeHostname) GetEdgeHostname(options string, correlationid
 			edgeHostname.parent.GetEdgeHostnames(contract, group, "", correlationid)
 			newEdgeHostname, err := edgeHostname.parent.FindEdgeHostname(edgeHostname)
 			if err != nil || newEdgeHostname == nil {
-				return client.NewAPIError(res)
+				return apiErr
 			}
 
 			edgeHostname.EdgeHostnameID = newEdgeHostname.EdgeHostnameID
@@ -253,7 +255,7 @@ func (edgeHostname *EdgeHostname) GetEdgeHostname(options string, correlationid
 			return nil
 		}
 
-		return client.NewAPIError(res)
+		return apiErr
 	}
 
 	newEdgeHostnames := NewEdgeHostnames()
@@ -483,6 +483,7 @@ func (property *Property) Delete(correlationid string) error {
 	if err != nil {
 		return err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponseCorrelation(res, true, correlationid)
 
@@ -99,6 +99,7 @@ func (ruleFormats *RuleFormats) GetSchema(product string, ruleFormat string, cor
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponseCorrelation(res, true, correlationid)
 
@@ -106,6 +106,7 @@ func (rules *Rules) GetRulesDigest(property *Property, correlationid string) (st
 	if err != nil {
 		return "", err
 	}
+	defer client.DrainBody(res)
 
 	edge.PrintHttpResponseCorrelation(res, true, correlationid)
 
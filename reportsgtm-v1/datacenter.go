@@ -82,6 +82,7 @@ func GetTrafficPerDatacenter(domainName string, datacenterID int, optArgs map[st
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	// print/log the response if warranted
 	printHttpResponse(res, true)
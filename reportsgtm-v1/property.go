@@ -131,6 +131,7 @@ func GetIpStatusPerProperty(domainName string, propertyName string, optArgs map[
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	// print/log the response if warranted
 	printHttpResponse(res, true)
@@ -191,6 +192,7 @@ func GetTrafficPerProperty(domainName string, propertyName string, optArgs map[s
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	// print/log the response if warranted
 	printHttpResponse(res, true)
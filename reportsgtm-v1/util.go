@@ -79,6 +79,7 @@ func getWindowCore(hostURL string) (*WindowResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer client.DrainBody(res)
 
 	printHttpResponse(res, true)
 